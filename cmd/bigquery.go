@@ -2,13 +2,16 @@ package cmd
 
 import (
 	"context"
-	"flag"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"time"
 
 	bq "cloud.google.com/go/bigquery"
-	"github.com/ipfs-shipyard/equinix-billing-tools/common"
 	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+	"google.golang.org/api/googleapi"
 )
 
 type usageRecord struct {
@@ -40,79 +43,143 @@ func (r usageRecord) Save() (map[string]bq.Value, string, error) {
 	}, bq.NoDedupeID, nil
 }
 
+// dryRunRecord mirrors the fields usageRecord.Save emits, exported so
+// --dry-run can marshal it to JSON.
+type dryRunRecord struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Project   string    `json:"project"`
+	Metro     string    `json:"metro"`
+	Plan      string    `json:"plan"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	Quantity  float64   `json:"quantity"`
+	Total     float64   `json:"total"`
+}
+
+func (r usageRecord) dryRun() dryRunRecord {
+	return dryRunRecord{
+		StartTime: r.startTime,
+		EndTime:   r.endTime,
+		Project:   r.project,
+		Metro:     r.metro,
+		Plan:      r.plan,
+		Type:      r.tpe,
+		Name:      r.name,
+		Price:     r.price,
+		Quantity:  r.quantity,
+		Total:     r.total,
+	}
+}
+
+// usageRecordSchema is the canonical BigQuery schema for the fields
+// usageRecord.Save emits. It is compared against the destination table's
+// remote schema up front, and used to create the table when --create-table
+// is given.
+var usageRecordSchema = bq.Schema{
+	{Name: "start_time", Type: bq.TimestampFieldType},
+	{Name: "end_time", Type: bq.TimestampFieldType},
+	{Name: "project", Type: bq.StringFieldType},
+	{Name: "metro", Type: bq.StringFieldType},
+	{Name: "plan", Type: bq.StringFieldType},
+	{Name: "type", Type: bq.StringFieldType},
+	{Name: "name", Type: bq.StringFieldType},
+	{Name: "price", Type: bq.FloatFieldType},
+	{Name: "quantity", Type: bq.FloatFieldType},
+	{Name: "total", Type: bq.FloatFieldType},
+}
+
 type UploadToBigqueryT struct {
-	equinix   equinix.Equinix
-	startTime time.Time
-	endTime   time.Time
-	projectId string
-	datasetId string
-	tableId   string
+	equinix     equinix.Equinix
+	startTime   time.Time
+	endTime     time.Time
+	projectId   string
+	datasetId   string
+	tableId     string
+	dryRun      bool
+	createTable bool
 }
 
-func UploadToBigquery(eq equinix.Equinix) Command {
-	cmd := flag.NewFlagSet("bigquery", flag.ExitOnError)
+func UploadToBigquery(args []string) (Command, error) {
+	r, err := NewRegistry("bigquery", args)
+	if err != nil {
+		return nil, err
+	}
 
-	helpF := cmd.Bool("h", false, "Show this help")
-	startF := cmd.String("s", time.Now().AddDate(0, 0, -2).Format(common.ISO8601_FORMAT), "Start time in ISO8601 format")
-	secondsF := cmd.Int64("i", 86400, "Time interval in seconds")
-	projectIdF := cmd.String("p", "", "Project ID (mandatory)")
-	datasetIdF := cmd.String("d", "", "Dataset ID (mandatory)")
-	tableIdF := cmd.String("t", "", "Table ID (mandatory)")
+	helpF := r.Flags.Bool("h", false, "Show this help")
+	projectIdF := r.Flags.String("p", "", "Project ID (mandatory)")
+	datasetIdF := r.Flags.String("d", "", "Dataset ID (mandatory)")
+	tableIdF := r.Flags.String("t", "", "Table ID (mandatory)")
+	dryRunF := r.Flags.Bool("dry-run", false, "Print the row batch that would be inserted, as JSON, instead of writing to BigQuery")
+	createTableF := r.Flags.Bool("create-table", false, "Create the destination table with the canonical schema if it doesn't already exist")
 
-	cmd.Parse(os.Args[2:])
+	if err := r.Parse(args); err != nil {
+		return nil, err
+	}
 
 	if *helpF {
-		cmd.Usage()
+		r.Flags.Usage()
 		os.Exit(0)
 	}
 
-	var startTime time.Time
-	var err error
+	if err := r.Resolve(); err != nil {
+		return nil, err
+	}
 
-	startTime, err = common.ParsePartialIsoTime(*startF)
+	startTime, ok, err := r.Start()
 	if err != nil {
-		log.Errorf("Invalid end time %s, it must be in ISO8601 format: %s", *startF, err.Error())
-		os.Exit(1)
+		return nil, fmt.Errorf("invalid start time: %w", err)
+	}
+	if !ok {
+		startTime = time.Now().AddDate(0, 0, -2)
 	}
 
-	endTime := startTime.Add(time.Duration(*secondsF) * time.Second)
+	endTime := startTime.AddDate(0, 0, r.Days())
+
+	if *projectIdF == "" || *datasetIdF == "" || *tableIdF == "" {
+		return nil, fmt.Errorf("-p, -d, and -t are all mandatory")
+	}
 
 	log.Infof("Inserting from %v to %v", startTime, endTime)
 
-	// TODO Validate project.dataset.table
 	// TODO Dockerfile
 
 	return UploadToBigqueryT{
-		equinix:   eq,
-		startTime: startTime,
-		endTime:   endTime,
-		projectId: *projectIdF,
-		datasetId: *datasetIdF,
-		tableId:   *tableIdF,
-	}
+		equinix:     equinix.Equinix{Token: r.Token},
+		startTime:   startTime,
+		endTime:     endTime,
+		projectId:   *projectIdF,
+		datasetId:   *datasetIdF,
+		tableId:     *tableIdF,
+		dryRun:      *dryRunF,
+		createTable: *createTableF,
+	}, nil
 }
 
-func (up UploadToBigqueryT) Run() {
-	projects, err := up.equinix.GetProjects()
+func (up UploadToBigqueryT) Run(ctx context.Context) error {
+	client, err := bq.NewClient(ctx, up.projectId)
 	if err != nil {
-		log.Error("Error while getting project list\n%s", err.Error())
-		os.Exit(1)
+		return fmt.Errorf("error while creating BigQuery client: %w", err)
 	}
+	defer client.Close()
+	table := client.Dataset(up.datasetId).Table(up.tableId)
 
-	projUsages, err := up.equinix.GetUsages(up.startTime, up.endTime, projects)
+	if err := up.checkOrCreateTable(ctx, table); err != nil {
+		return err
+	}
+
+	projects, err := up.equinix.GetProjects(ctx)
 	if err != nil {
-		log.Error("Error while getting usages\n%s", err.Error())
-		os.Exit(1)
+		return fmt.Errorf("error while getting project list: %w", err)
 	}
 
-	ctx := context.Background()
-	client, err := bq.NewClient(ctx, up.projectId)
+	projUsages, err := up.equinix.GetUsages(ctx, up.startTime, up.endTime, projects)
 	if err != nil {
-		log.Error("Error while creating BigQuery client\n%s", err.Error())
-		os.Exit(1)
+		return fmt.Errorf("error while getting usages: %w", err)
 	}
-	defer client.Close()
-	inserter := client.Dataset(up.datasetId).Table(up.tableId).Inserter()
+
+	inserter := table.Inserter()
 
 	for project, usages := range projUsages {
 		items := make([]usageRecord, 0, len(usages))
@@ -151,11 +218,103 @@ func (up UploadToBigqueryT) Run() {
 			items = append(items, bqU)
 		}
 
+		if up.dryRun {
+			log.Infof("%s: would insert %d records (dry run)", project, len(items))
+			if err := printDryRunBatch(project, items); err != nil {
+				return fmt.Errorf("error while marshaling dry-run output: %w", err)
+			}
+			continue
+		}
+
 		log.Infof("%s: inserting %d records", project, len(items))
 
-		if err = inserter.Put(ctx, items); err != nil {
-			log.Error("Error while bulk-inserting items to BigQuery\n%s\n", err.Error())
-			os.Exit(1)
+		if err := inserter.Put(ctx, items); err != nil {
+			return fmt.Errorf("error while bulk-inserting items to BigQuery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkOrCreateTable validates that table's remote schema matches
+// usageRecordSchema, creating the table with that schema first if
+// --create-table was given and the table doesn't exist yet. This runs
+// before any Equinix data is fetched, so a misconfigured destination
+// fails fast instead of surfacing as an opaque streaming-insert error.
+// Under --dry-run, a missing table is reported rather than created, since
+// dry runs must not have side effects.
+func (up UploadToBigqueryT) checkOrCreateTable(ctx context.Context, table *bq.Table) error {
+	meta, err := table.Metadata(ctx)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if up.createTable && errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			if up.dryRun {
+				log.Infof("Table %s.%s does not exist; --create-table would create it (dry run)", up.datasetId, up.tableId)
+				return nil
+			}
+
+			if err := table.Create(ctx, &bq.TableMetadata{Schema: usageRecordSchema}); err != nil {
+				return fmt.Errorf("error while creating table %s.%s: %w", up.datasetId, up.tableId, err)
+			}
+			log.Infof("Created table %s.%s", up.datasetId, up.tableId)
+			return nil
 		}
+
+		return fmt.Errorf("error while fetching metadata for table %s.%s: %w", up.datasetId, up.tableId, err)
 	}
+
+	if missing, extra, mismatched := diffSchema(meta.Schema, usageRecordSchema); len(missing) > 0 || len(extra) > 0 || len(mismatched) > 0 {
+		return fmt.Errorf("table %s.%s schema does not match the expected columns (missing: %v, extra: %v, wrong type: %v)", up.datasetId, up.tableId, missing, extra, mismatched)
+	}
+
+	return nil
+}
+
+// diffSchema compares remote against want by field name and type,
+// returning the names present in want but missing from remote, the names
+// present in remote but not in want, and the names present in both but
+// with different types.
+func diffSchema(remote bq.Schema, want bq.Schema) (missing []string, extra []string, mismatched []string) {
+	remoteTypes := make(map[string]bq.FieldType, len(remote))
+	for _, f := range remote {
+		remoteTypes[f.Name] = f.Type
+	}
+
+	wantNames := make(map[string]bool, len(want))
+	for _, f := range want {
+		wantNames[f.Name] = true
+	}
+
+	for _, f := range want {
+		remoteType, ok := remoteTypes[f.Name]
+		if !ok {
+			missing = append(missing, f.Name)
+		} else if remoteType != f.Type {
+			mismatched = append(mismatched, f.Name)
+		}
+	}
+	for _, f := range remote {
+		if !wantNames[f.Name] {
+			extra = append(extra, f.Name)
+		}
+	}
+
+	return missing, extra, mismatched
+}
+
+// printDryRunBatch prints the batch of rows that would have been inserted
+// for project, marshaled as JSON, instead of calling inserter.Put.
+func printDryRunBatch(project string, items []usageRecord) error {
+	records := make([]dryRunRecord, len(items))
+	for i, item := range items {
+		records[i] = item.dryRun()
+	}
+
+	b, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("-- dry run: %s (%d records) --\n%s\n", project, len(items), b)
+	return nil
 }
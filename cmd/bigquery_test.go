@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	bq "cloud.google.com/go/bigquery"
+)
+
+func TestDiffSchemaReportsMissingExtraAndMismatched(t *testing.T) {
+	remote := bq.Schema{
+		{Name: "start_time", Type: bq.TimestampFieldType},
+		{Name: "project", Type: bq.StringFieldType},
+		{Name: "price", Type: bq.StringFieldType},
+		{Name: "legacy_column", Type: bq.StringFieldType},
+	}
+	want := bq.Schema{
+		{Name: "start_time", Type: bq.TimestampFieldType},
+		{Name: "project", Type: bq.StringFieldType},
+		{Name: "price", Type: bq.FloatFieldType},
+		{Name: "total", Type: bq.FloatFieldType},
+	}
+
+	missing, extra, mismatched := diffSchema(remote, want)
+
+	if !reflect.DeepEqual(missing, []string{"total"}) {
+		t.Fatalf("missing = %v, want [total]", missing)
+	}
+	if !reflect.DeepEqual(extra, []string{"legacy_column"}) {
+		t.Fatalf("extra = %v, want [legacy_column]", extra)
+	}
+	if !reflect.DeepEqual(mismatched, []string{"price"}) {
+		t.Fatalf("mismatched = %v, want [price]", mismatched)
+	}
+}
+
+func TestDiffSchemaMatches(t *testing.T) {
+	missing, extra, mismatched := diffSchema(usageRecordSchema, usageRecordSchema)
+	if len(missing) != 0 || len(extra) != 0 || len(mismatched) != 0 {
+		t.Fatalf("expected no diff, got missing=%v extra=%v mismatched=%v", missing, extra, mismatched)
+	}
+}
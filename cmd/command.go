@@ -0,0 +1,12 @@
+package cmd
+
+import "context"
+
+// Command is implemented by every subcommand constructed from os.Args. The
+// constructor does argument parsing and validation, returning an error for
+// malformed input. Run performs the actual work and reports failure by
+// returning an error rather than calling os.Exit, so main.go can centralize
+// exit codes and logging.
+type Command interface {
+	Run(ctx context.Context) error
+}
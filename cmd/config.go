@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config provides defaults for the common Registry flags, loaded from a
+// TOML file named by --config. Any flag given explicitly on the command
+// line overrides the corresponding Config field, so CI jobs can keep long
+// argv lines out of their job definitions while still overriding a value
+// for a one-off run.
+type Config struct {
+	Start     string `toml:"start"`
+	End       string `toml:"end"`
+	Days      int    `toml:"days"`
+	LogLevel  string `toml:"log_level"`
+	TokenFile string `toml:"token_file"`
+}
+
+// LoadConfig reads a Config from path. An empty path returns the zero
+// Config, so --config is optional.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	if path == "" {
+		return cfg, nil
+	}
+
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return Config{}, fmt.Errorf("error while reading config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultTokenFile is checked, relative to the user's home directory, when
+// neither --token-file nor EQUINIX_TOKEN is set.
+const defaultTokenFile = ".config/equinix/token"
+
+// ResolveToken finds the Equinix API token, preferring, in order: the file
+// named by tokenFile (usually from --token-file), the EQUINIX_TOKEN
+// environment variable, and finally ~/.config/equinix/token.
+func ResolveToken(tokenFile string) (string, error) {
+	if tokenFile != "" {
+		return readTokenFile(tokenFile)
+	}
+
+	if token := os.Getenv("EQUINIX_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		if token, err := readTokenFile(filepath.Join(home, defaultTokenFile)); err == nil {
+			return token, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"no Equinix API token found: set --token-file, EQUINIX_TOKEN, or put it in %s",
+		filepath.Join("~", defaultTokenFile),
+	)
+}
+
+func readTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveTokenPrefersTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("error while writing token file: %s", err)
+	}
+
+	t.Setenv("EQUINIX_TOKEN", "from-env")
+
+	token, err := ResolveToken(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token != "from-file" {
+		t.Fatalf("ResolveToken() = %q, want %q", token, "from-file")
+	}
+}
+
+func TestResolveTokenFallsBackToEnv(t *testing.T) {
+	t.Setenv("EQUINIX_TOKEN", "from-env")
+
+	token, err := ResolveToken("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token != "from-env" {
+		t.Fatalf("ResolveToken() = %q, want %q", token, "from-env")
+	}
+}
+
+func TestResolveTokenErrorsWhenNotFound(t *testing.T) {
+	t.Setenv("EQUINIX_TOKEN", "")
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := ResolveToken(""); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
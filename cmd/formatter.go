@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/report"
+)
+
+// SummaryResult bundles a cost summary with the request options that
+// produced it, so formatters can render date headers and the like.
+type SummaryResult struct {
+	Options    report.SummaryOptions
+	PerProject map[string]report.SummaryRecord
+	Totals     report.SummaryRecord
+}
+
+// Formatter renders a SummaryResult to w.
+type Formatter interface {
+	Format(w io.Writer, result SummaryResult) error
+}
+
+// NewFormatter returns the Formatter registered under name, or an error if
+// name isn't one of "text", "csv", "json", or "html". An empty name selects
+// "text".
+func NewFormatter(name string) (Formatter, error) {
+	switch name {
+	case "", "text":
+		return textFormatter{}, nil
+	case "csv":
+		return csvFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "html":
+		return htmlFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, must be one of: text, csv, json, html", name)
+	}
+}
+
+// sortedProjects returns the project names in perProject, sorted so
+// formatter output is deterministic.
+func sortedProjects(perProject map[string]report.SummaryRecord) []string {
+	names := make([]string, 0, len(perProject))
+	for name := range perProject {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// percentDelta returns the percent change from BaseTotal to Total, or 0 if
+// BaseTotal is 0 (e.g. a new project with no baseline usage) rather than
+// dividing by zero, which would produce a NaN/Inf that encoding/json cannot
+// marshal.
+func percentDelta(summary report.SummaryRecord) float64 {
+	if summary.BaseTotal == 0 {
+		return 0
+	}
+	return 100.0 * (summary.Total - summary.BaseTotal) / summary.BaseTotal
+}
+
+type textFormatter struct{}
+
+func (textFormatter) Format(w io.Writer, result SummaryResult) error {
+	fmt.Fprintf(
+		w,
+		"%-15.15s %11s %11s\n",
+		"Project",
+		result.Options.BaselineEnd.Format("2006-01-02"),
+		result.Options.End.Format("2006-01-02"),
+	)
+
+	p := message.NewPrinter(language.English)
+	for _, project := range sortedProjects(result.PerProject) {
+		summary := result.PerProject[project]
+		p.Fprintf(
+			w,
+			"%-15.15s %11.2f %11.2f %+7.2f%%\n",
+			project,
+			summary.BaseTotal,
+			summary.Total,
+			percentDelta(summary),
+		)
+	}
+
+	p.Fprintf(
+		w,
+		"%-15.15s %11.2f %11.2f %+7.2f%%\n",
+		"Total",
+		result.Totals.BaseTotal,
+		result.Totals.Total,
+		percentDelta(result.Totals),
+	)
+
+	return nil
+}
+
+type csvFormatter struct{}
+
+func (csvFormatter) Format(w io.Writer, result SummaryResult) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"project", "price", "quantity", "total", "base_price", "base_quantity", "base_total", "percent_delta"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	writeRow := func(project string, summary report.SummaryRecord) error {
+		return cw.Write([]string{
+			project,
+			strconv.FormatFloat(summary.Price, 'f', 2, 64),
+			strconv.FormatFloat(summary.Quantity, 'f', 2, 64),
+			strconv.FormatFloat(summary.Total, 'f', 2, 64),
+			strconv.FormatFloat(summary.BasePrice, 'f', 2, 64),
+			strconv.FormatFloat(summary.BaseQuantity, 'f', 2, 64),
+			strconv.FormatFloat(summary.BaseTotal, 'f', 2, 64),
+			strconv.FormatFloat(percentDelta(summary), 'f', 2, 64),
+		})
+	}
+
+	for _, project := range sortedProjects(result.PerProject) {
+		if err := writeRow(project, result.PerProject[project]); err != nil {
+			return err
+		}
+	}
+	if err := writeRow("Total", result.Totals); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonFormatter struct{}
+
+type jsonProjectSummary struct {
+	Project      string  `json:"project"`
+	Price        float64 `json:"price"`
+	Quantity     float64 `json:"quantity"`
+	Total        float64 `json:"total"`
+	BasePrice    float64 `json:"base_price"`
+	BaseQuantity float64 `json:"base_quantity"`
+	BaseTotal    float64 `json:"base_total"`
+	PercentDelta float64 `json:"percent_delta"`
+}
+
+func toJSONProjectSummary(project string, summary report.SummaryRecord) jsonProjectSummary {
+	return jsonProjectSummary{
+		Project:      project,
+		Price:        summary.Price,
+		Quantity:     summary.Quantity,
+		Total:        summary.Total,
+		BasePrice:    summary.BasePrice,
+		BaseQuantity: summary.BaseQuantity,
+		BaseTotal:    summary.BaseTotal,
+		PercentDelta: percentDelta(summary),
+	}
+}
+
+func (jsonFormatter) Format(w io.Writer, result SummaryResult) error {
+	projects := make([]jsonProjectSummary, 0, len(result.PerProject))
+	for _, project := range sortedProjects(result.PerProject) {
+		projects = append(projects, toJSONProjectSummary(project, result.PerProject[project]))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Projects []jsonProjectSummary `json:"projects"`
+		Total    jsonProjectSummary   `json:"total"`
+	}{
+		Projects: projects,
+		Total:    toJSONProjectSummary("Total", result.Totals),
+	})
+}
+
+type htmlFormatter struct{}
+
+var htmlSummaryTemplate = template.Must(template.New("summary").Parse(strings.TrimSpace(`
+<table>
+  <thead>
+    <tr><th>Project</th><th>{{.Options.BaselineEnd.Format "2006-01-02"}}</th><th>{{.Options.End.Format "2006-01-02"}}</th><th>Delta</th></tr>
+  </thead>
+  <tbody>
+    {{range .Rows}}<tr><td>{{.Project}}</td><td>{{printf "%.2f" .BaseTotal}}</td><td>{{printf "%.2f" .Total}}</td><td class="{{if ge .PercentDelta 0.0}}delta-up{{else}}delta-down{{end}}">{{printf "%+.2f%%" .PercentDelta}}</td></tr>
+    {{end}}
+  </tbody>
+  <tfoot>
+    <tr><td>Total</td><td>{{printf "%.2f" .Totals.BaseTotal}}</td><td>{{printf "%.2f" .Totals.Total}}</td><td class="{{if ge .TotalsPercentDelta 0.0}}delta-up{{else}}delta-down{{end}}">{{printf "%+.2f%%" .TotalsPercentDelta}}</td></tr>
+  </tfoot>
+</table>
+`)))
+
+func (htmlFormatter) Format(w io.Writer, result SummaryResult) error {
+	type row struct {
+		Project string
+		report.SummaryRecord
+		PercentDelta float64
+	}
+
+	rows := make([]row, 0, len(result.PerProject))
+	for _, project := range sortedProjects(result.PerProject) {
+		summary := result.PerProject[project]
+		rows = append(rows, row{Project: project, SummaryRecord: summary, PercentDelta: percentDelta(summary)})
+	}
+
+	return htmlSummaryTemplate.Execute(w, struct {
+		Options            report.SummaryOptions
+		Rows               []row
+		Totals             report.SummaryRecord
+		TotalsPercentDelta float64
+	}{
+		Options:            result.Options,
+		Rows:               rows,
+		Totals:             result.Totals,
+		TotalsPercentDelta: percentDelta(result.Totals),
+	})
+}
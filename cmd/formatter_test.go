@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/report"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func testResult() SummaryResult {
+	return SummaryResult{
+		Options: report.SummaryOptions{
+			BaselineEnd: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:         time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		PerProject: map[string]report.SummaryRecord{
+			"alpha": {Price: 10, Quantity: 1, Total: 100, BasePrice: 8, BaseQuantity: 1, BaseTotal: 80},
+			"beta":  {Price: 5, Quantity: 2, Total: 50, BasePrice: 5, BaseQuantity: 2, BaseTotal: 50},
+			"gamma": {Price: 2, Quantity: 10, Total: 20, BasePrice: 0, BaseQuantity: 0, BaseTotal: 0},
+		},
+		Totals: report.SummaryRecord{Price: 15, Quantity: 3, Total: 150, BasePrice: 13, BaseQuantity: 3, BaseTotal: 130},
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter Formatter
+		golden    string
+	}{
+		{"text", textFormatter{}, "testdata/summary.text.golden"},
+		{"csv", csvFormatter{}, "testdata/summary.csv.golden"},
+		{"json", jsonFormatter{}, "testdata/summary.json.golden"},
+		{"html", htmlFormatter{}, "testdata/summary.html.golden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.formatter.Format(&buf, testResult()); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if *update {
+				if err := os.WriteFile(tt.golden, buf.Bytes(), 0644); err != nil {
+					t.Fatalf("error while updating golden file: %s", err)
+				}
+			}
+
+			want, err := os.ReadFile(tt.golden)
+			if err != nil {
+				t.Fatalf("error while reading golden file: %s", err)
+			}
+
+			if buf.String() != string(want) {
+				t.Fatalf("output does not match golden file %s\ngot:\n%s\nwant:\n%s", tt.golden, buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestPercentDeltaZeroBaseline(t *testing.T) {
+	got := percentDelta(report.SummaryRecord{Total: 20, BaseTotal: 0})
+	if got != 0 {
+		t.Fatalf("expected 0 for a zero baseline, got %v", got)
+	}
+}
+
+func TestNewFormatterRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewFormatter("yaml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
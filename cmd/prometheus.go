@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+	"github.com/ipfs-shipyard/equinix-billing-tools/report"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultScrapeInterval is how often the prometheus command polls Equinix
+// for fresh usage data, absent --scrape-interval.
+const DefaultScrapeInterval = time.Hour
+
+// PrometheusWindowDays and PrometheusBaselineDays size the sliding window
+// the exporter tracks: the trailing PrometheusWindowDays days, compared
+// against the PrometheusBaselineDays days immediately before that window.
+const (
+	PrometheusWindowDays   = 7
+	PrometheusBaselineDays = 7
+)
+
+var (
+	projectCost = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "equinix_project_cost_total",
+		Help: "Total cost accrued over the current sliding window, by project/metro/plan/type.",
+	}, []string{"project", "metro", "plan", "type"})
+
+	projectCostBaseline = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "equinix_project_cost_baseline_total",
+		Help: "Total cost accrued over the baseline window preceding the current one, by project/metro/plan/type.",
+	}, []string{"project", "metro", "plan", "type"})
+
+	lastScrapeTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "equinix_last_scrape_timestamp_seconds",
+		Help: "Unix timestamp of the last successful scrape of the Equinix API.",
+	})
+
+	scrapeErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "equinix_scrape_errors_total",
+		Help: "Number of scrapes that failed to fetch usage data from Equinix.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(projectCost, projectCostBaseline, lastScrapeTimestamp, scrapeErrorsTotal)
+}
+
+// PrometheusT serves a Prometheus /metrics endpoint, refreshed on
+// --scrape-interval by polling the Equinix API for a rolling window of
+// usage. Because today's usage numbers change intra-day, only days that
+// have fully elapsed are frozen in freezeCache; today is always re-fetched.
+type PrometheusT struct {
+	addr           string
+	reporter       report.ReportAPI
+	scrapeInterval time.Duration
+
+	mu          sync.Mutex
+	freezeCache map[string]map[string][]equinix.UsageRecord // day (YYYY-MM-DD) -> project -> usages
+}
+
+func Prometheus(args []string) (Command, error) {
+	r, err := NewRegistry("prometheus", args)
+	if err != nil {
+		return nil, err
+	}
+
+	helpF := r.Flags.Bool("h", false, "Show this help")
+	addrF := r.Flags.String("l", ":9091", "Address to listen on")
+	scrapeIntervalF := r.Flags.Duration("scrape-interval", DefaultScrapeInterval, "How often to poll Equinix for fresh usage data")
+
+	if err := r.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *helpF {
+		r.Flags.Usage()
+		os.Exit(0)
+	}
+
+	if err := r.Resolve(); err != nil {
+		return nil, err
+	}
+
+	return &PrometheusT{
+		addr: *addrF,
+		// The reporter's TTL cache is disabled: elapsed days are kept in
+		// freezeCache, and today must be fetched fresh on every scrape.
+		reporter:       report.NewReporter(equinix.Equinix{Token: r.Token}, 0),
+		scrapeInterval: *scrapeIntervalF,
+		freezeCache:    make(map[string]map[string][]equinix.UsageRecord),
+	}, nil
+}
+
+func (p *PrometheusT) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	server := &http.Server{Addr: p.addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := p.scrape(ctx); err != nil {
+		log.Errorf("Initial scrape failed: %s", err.Error())
+		scrapeErrorsTotal.Inc()
+	}
+
+	go p.scrapeLoop(ctx)
+
+	log.Infof("Listening on %s", p.addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("error while serving HTTP: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PrometheusT) scrapeLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.scrapeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.scrape(ctx); err != nil {
+				log.Errorf("Scrape failed: %s", err.Error())
+				scrapeErrorsTotal.Inc()
+			}
+		}
+	}
+}
+
+// scrape refreshes the exported gauges from the current window and
+// baseline window of Equinix usage.
+func (p *PrometheusT) scrape(ctx context.Context) error {
+	projects, err := p.reporter.Projects(ctx)
+	if err != nil {
+		return fmt.Errorf("error while getting project list: %w", err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	windowStart := today.AddDate(0, 0, -PrometheusWindowDays+1)
+	baselineEnd := windowStart.AddDate(0, 0, -1)
+	baselineStart := baselineEnd.AddDate(0, 0, -PrometheusBaselineDays+1)
+
+	window, err := p.usagesByDay(ctx, projects, windowStart, today)
+	if err != nil {
+		return fmt.Errorf("error while getting current window usages: %w", err)
+	}
+
+	baseline, err := p.usagesByDay(ctx, projects, baselineStart, baselineEnd)
+	if err != nil {
+		return fmt.Errorf("error while getting baseline window usages: %w", err)
+	}
+
+	projectCost.Reset()
+	projectCostBaseline.Reset()
+	setUsageTotals(projectCost, window)
+	setUsageTotals(projectCostBaseline, baseline)
+
+	lastScrapeTimestamp.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+// usagesByDay returns usage records for projects across [start, end],
+// fetching one day at a time so that days which have fully elapsed by the
+// time they're first fetched can be frozen in p.freezeCache and never
+// refetched, while today's day is always fetched fresh.
+func (p *PrometheusT) usagesByDay(ctx context.Context, projects []equinix.Project, start time.Time, end time.Time) (map[string][]equinix.UsageRecord, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	result := make(map[string][]equinix.UsageRecord)
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+
+		p.mu.Lock()
+		usages, frozen := p.freezeCache[key]
+		p.mu.Unlock()
+
+		if !frozen {
+			fetched, err := p.reporter.UsagesForProjects(ctx, projects, day, day)
+			if err != nil {
+				return nil, err
+			}
+			usages = fetched
+
+			if day.Before(today) {
+				p.mu.Lock()
+				p.freezeCache[key] = fetched
+				p.mu.Unlock()
+			}
+		}
+
+		for project, records := range usages {
+			result[project] = append(result[project], records...)
+		}
+	}
+
+	return result, nil
+}
+
+// setUsageTotals sums usages by project/metro/plan/type and sets each
+// combination's gauge to that sum.
+func setUsageTotals(gauge *prometheus.GaugeVec, usages map[string][]equinix.UsageRecord) {
+	type key struct{ project, metro, plan, typ string }
+
+	totals := make(map[key]float64)
+
+	for project, records := range usages {
+		for _, u := range records {
+			totals[key{project, u.Metro, u.Plan, u.Type}] += u.Total
+		}
+	}
+
+	for k, total := range totals {
+		gauge.WithLabelValues(k.project, k.metro, k.plan, k.typ).Set(total)
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetUsageTotalsSumsByLabels(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge"}, []string{"project", "metro", "plan", "type"})
+
+	usages := map[string][]equinix.UsageRecord{
+		"proj": {
+			{Metro: "sv", Plan: "c3.small", Type: "Usage", Total: 10},
+			{Metro: "sv", Plan: "c3.small", Type: "Usage", Total: 5},
+			{Metro: "sv", Plan: "c3.small", Type: "HardwareReservation", Total: 2},
+		},
+	}
+
+	setUsageTotals(gauge, usages)
+
+	got := testutil.ToFloat64(gauge.WithLabelValues("proj", "sv", "c3.small", "Usage"))
+	if got != 15 {
+		t.Fatalf("Usage total = %v, want 15", got)
+	}
+
+	got = testutil.ToFloat64(gauge.WithLabelValues("proj", "sv", "c3.small", "HardwareReservation"))
+	if got != 2 {
+		t.Fatalf("HardwareReservation total = %v, want 2", got)
+	}
+}
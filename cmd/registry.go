@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/common"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("equinix-billing-tools")
+
+// Registry owns the flags shared by every subcommand -- --start, --end,
+// --days, --config, --log-level, and --token-file -- so commands don't
+// each reimplement date parsing and credential lookup with their own flag
+// names and defaults. Subcommands add their own flags to r.Flags before
+// calling Parse.
+type Registry struct {
+	Flags *flag.FlagSet
+
+	// Token is the resolved Equinix API token, populated by Resolve.
+	Token string
+
+	startF     *string
+	endF       *string
+	daysF      *int
+	logLevelF  *string
+	tokenFileF *string
+}
+
+// NewRegistry creates a FlagSet named after the subcommand, seeds its
+// common flags with defaults loaded from --config (if present in args),
+// and registers them. args is scanned only for --config here; the full
+// parse happens in Parse, once the subcommand has added its own flags.
+func NewRegistry(name string, args []string) (*Registry, error) {
+	cfg, err := LoadConfig(peekFlag(args, "config"))
+	if err != nil {
+		return nil, err
+	}
+
+	end := cfg.End
+	if end == "" {
+		end = time.Now().AddDate(0, 0, -2).Format("2006-01-02")
+	}
+
+	days := cfg.Days
+	if days == 0 {
+		days = 1
+	}
+
+	logLevel := cfg.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	r := &Registry{Flags: fs}
+
+	r.startF = fs.String("start", cfg.Start, "Start date/time, in YYYY-MM-DD or ISO8601 format")
+	r.endF = fs.String("end", end, "End date/time, in YYYY-MM-DD or ISO8601 format (default: 2 days ago)")
+	r.daysF = fs.Int("days", days, "Number of days to aggregate (cost_summary counts back from --end, bigquery counts forward from --start)")
+	r.logLevelF = fs.String("log-level", logLevel, "Log level: debug, info, warn, or error")
+	r.tokenFileF = fs.String("token-file", cfg.TokenFile, "Path to a file containing the Equinix API token")
+	fs.String("config", "", "Path to a TOML config file providing defaults for these flags")
+
+	return r, nil
+}
+
+// Parse parses args and applies --log-level to every logger. Subcommands
+// should check their own -h flag after Parse, before calling Resolve, so
+// -h doesn't require an Equinix token to be configured.
+func (r *Registry) Parse(args []string) error {
+	if err := r.Flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.SetLogLevel("*", *r.logLevelF); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", *r.logLevelF, err)
+	}
+
+	return nil
+}
+
+// Resolve populates r.Token via ResolveToken. It is separate from Parse so
+// constructors can handle -h before requiring a token.
+func (r *Registry) Resolve() error {
+	token, err := ResolveToken(*r.tokenFileF)
+	if err != nil {
+		return err
+	}
+	r.Token = token
+
+	return nil
+}
+
+// Days returns the --days flag value.
+func (r *Registry) Days() int {
+	return *r.daysF
+}
+
+// End parses the --end flag as a partial ISO timestamp.
+func (r *Registry) End() (time.Time, error) {
+	return common.ParsePartialIsoTime(*r.endF)
+}
+
+// Start parses the --start flag as a partial ISO timestamp. ok is false if
+// --start was not given, since it has no default shared by every command.
+func (r *Registry) Start() (t time.Time, ok bool, err error) {
+	if *r.startF == "" {
+		return time.Time{}, false, nil
+	}
+
+	t, err = common.ParsePartialIsoTime(*r.startF)
+	return t, true, err
+}
+
+// peekFlag scans args for -name/--name VALUE or -name=VALUE/--name=VALUE,
+// without fully parsing args, so its result can seed a FlagSet's defaults
+// before the FlagSet itself is defined and parsed.
+func peekFlag(args []string, name string) string {
+	for i, arg := range args {
+		for _, prefix := range [2]string{"-" + name, "--" + name} {
+			if arg == prefix && i+1 < len(args) {
+				return args[i+1]
+			}
+			if len(arg) > len(prefix) && arg[:len(prefix)+1] == prefix+"=" {
+				return arg[len(prefix)+1:]
+			}
+		}
+	}
+	return ""
+}
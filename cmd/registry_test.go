@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeekFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing", []string{"-d", "2"}, ""},
+		{"space separated", []string{"--config", "a.toml"}, "a.toml"},
+		{"short flag", []string{"-config", "a.toml"}, "a.toml"},
+		{"equals form", []string{"--config=a.toml"}, "a.toml"},
+		{"trailing with no value", []string{"-d", "2", "--config"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peekFlag(tt.args, "config"); got != tt.want {
+				t.Fatalf("peekFlag(%v, \"config\") = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryParseAppliesConfigDefaults(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configPath, []byte("days = 5\nlog_level = \"debug\"\n"), 0644); err != nil {
+		t.Fatalf("error while writing config file: %s", err)
+	}
+
+	t.Setenv("EQUINIX_TOKEN", "test-token")
+
+	args := []string{"--config", configPath}
+
+	r, err := NewRegistry("test", args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := r.Parse(args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := r.Resolve(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got := r.Days(); got != 5 {
+		t.Fatalf("Days() = %d, want 5", got)
+	}
+
+	if r.Token != "test-token" {
+		t.Fatalf("Token = %q, want %q", r.Token, "test-token")
+	}
+}
+
+func TestRegistryFlagOverridesConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configPath, []byte("days = 5\n"), 0644); err != nil {
+		t.Fatalf("error while writing config file: %s", err)
+	}
+
+	t.Setenv("EQUINIX_TOKEN", "test-token")
+
+	args := []string{"--config", configPath, "--days", "9"}
+
+	r, err := NewRegistry("test", args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := r.Parse(args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got := r.Days(); got != 9 {
+		t.Fatalf("Days() = %d, want 9", got)
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/common"
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+	"github.com/ipfs-shipyard/equinix-billing-tools/report"
+)
+
+type ServeT struct {
+	addr     string
+	reporter report.ReportAPI
+	authUser string
+	authPass string
+}
+
+func Serve(args []string) (Command, error) {
+	r, err := NewRegistry("serve", args)
+	if err != nil {
+		return nil, err
+	}
+
+	helpF := r.Flags.Bool("h", false, "Show this help")
+	addrF := r.Flags.String("l", ":8080", "Address to listen on")
+	cacheTTLF := r.Flags.Duration("cache-ttl", DefaultCacheTTL, "How long to cache per-day Equinix responses")
+
+	if err := r.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *helpF {
+		r.Flags.Usage()
+		os.Exit(0)
+	}
+
+	if err := r.Resolve(); err != nil {
+		return nil, err
+	}
+
+	return ServeT{
+		addr:     *addrF,
+		reporter: report.NewReporter(equinix.Equinix{Token: r.Token}, *cacheTTLF),
+		authUser: os.Getenv("SERVE_AUTH_USER"),
+		authPass: os.Getenv("SERVE_AUTH_PASSWORD"),
+	}, nil
+}
+
+func (s ServeT) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/v1/summary", s.handleSummary)
+	mux.HandleFunc("/v1/projects", s.handleProjects)
+	mux.HandleFunc("/v1/usages", s.handleUsages)
+
+	server := &http.Server{
+		Addr:    s.addr,
+		Handler: s.withBasicAuth(mux),
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Infof("Listening on %s", s.addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("error while serving HTTP: %w", err)
+	}
+
+	return nil
+}
+
+func (s ServeT) withBasicAuth(next http.Handler) http.Handler {
+	if s.authUser == "" && s.authPass == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != s.authUser || pass != s.authPass {
+			w.Header().Set("WWW-Authenticate", `Basic realm="equinix-billing-tools"`)
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s ServeT) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func (s ServeT) handleSummary(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	start, err := common.ParsePartialIsoTime(q.Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid start: %s", err.Error()))
+		return
+	}
+
+	end, err := common.ParsePartialIsoTime(q.Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid end: %s", err.Error()))
+		return
+	}
+
+	baselineEnd := start.AddDate(0, 0, -1)
+	if raw := q.Get("baseline"); raw != "" {
+		baselineEnd, err = common.ParsePartialIsoTime(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid baseline: %s", err.Error()))
+			return
+		}
+	}
+
+	days := int(end.Sub(start).Hours()/24) + 1
+	baselineStart := baselineEnd.AddDate(0, 0, -days+1)
+
+	var reportType report.ReportType
+	switch q.Get("type") {
+	case "reservations":
+		reportType = report.ReservationsReport
+	case "":
+		reportType = report.NonReservationsReport
+	default:
+		writeError(w, http.StatusBadRequest, "invalid type, must be \"reservations\" or blank")
+		return
+	}
+
+	perProjectSummary, totals, err := s.reporter.Summary(r.Context(), report.SummaryOptions{
+		ReportType:    reportType,
+		Start:         start,
+		End:           end,
+		BaselineStart: baselineStart,
+		BaselineEnd:   baselineEnd,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"projects": perProjectSummary,
+		"total":    totals,
+	})
+}
+
+func (s ServeT) handleProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := s.reporter.Projects(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s ServeT) handleUsages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	project := q.Get("project")
+	if project == "" {
+		writeError(w, http.StatusBadRequest, "project is required")
+		return
+	}
+
+	start, err := common.ParsePartialIsoTime(q.Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid start: %s", err.Error()))
+		return
+	}
+
+	end, err := common.ParsePartialIsoTime(q.Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid end: %s", err.Error()))
+		return
+	}
+
+	usages, err := s.reporter.Usages(r.Context(), project, start, end)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usages)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
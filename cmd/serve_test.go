@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+	"github.com/ipfs-shipyard/equinix-billing-tools/report"
+)
+
+// fakeReportAPI is a report.ReportAPI stand-in that lets each test wire up
+// only the methods it exercises; unset methods panic if called.
+type fakeReportAPI struct {
+	summary  func(ctx context.Context, opts report.SummaryOptions) (map[string]report.SummaryRecord, report.SummaryRecord, error)
+	projects func(ctx context.Context) ([]equinix.Project, error)
+	usages   func(ctx context.Context, project string, start, end time.Time) ([]equinix.UsageRecord, error)
+}
+
+func (f fakeReportAPI) Summary(ctx context.Context, opts report.SummaryOptions) (map[string]report.SummaryRecord, report.SummaryRecord, error) {
+	return f.summary(ctx, opts)
+}
+
+func (f fakeReportAPI) Projects(ctx context.Context) ([]equinix.Project, error) {
+	return f.projects(ctx)
+}
+
+func (f fakeReportAPI) Usages(ctx context.Context, project string, start, end time.Time) ([]equinix.UsageRecord, error) {
+	return f.usages(ctx, project, start, end)
+}
+
+func (f fakeReportAPI) UsagesForProjects(ctx context.Context, projects []equinix.Project, start, end time.Time) (map[string][]equinix.UsageRecord, error) {
+	panic("not implemented")
+}
+
+func testServer(t *testing.T, s ServeT) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/v1/summary", s.handleSummary)
+	mux.HandleFunc("/v1/projects", s.handleProjects)
+	mux.HandleFunc("/v1/usages", s.handleUsages)
+
+	server := httptest.NewServer(s.withBasicAuth(mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHandleHealthz(t *testing.T) {
+	server := testServer(t, ServeT{})
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleProjects(t *testing.T) {
+	reporter := fakeReportAPI{
+		projects: func(ctx context.Context) ([]equinix.Project, error) {
+			return []equinix.Project{{Id: "1", Name: "one"}}, nil
+		},
+	}
+	server := testServer(t, ServeT{reporter: reporter})
+
+	resp, err := http.Get(server.URL + "/v1/projects")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var projects []equinix.Project
+	if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
+		t.Fatalf("error decoding response: %s", err)
+	}
+	if len(projects) != 1 || projects[0].Name != "one" {
+		t.Fatalf("unexpected projects: %+v", projects)
+	}
+}
+
+func TestHandleSummaryDefaultsBaselineAndDays(t *testing.T) {
+	var gotOpts report.SummaryOptions
+	reporter := fakeReportAPI{
+		summary: func(ctx context.Context, opts report.SummaryOptions) (map[string]report.SummaryRecord, report.SummaryRecord, error) {
+			gotOpts = opts
+			return map[string]report.SummaryRecord{}, report.SummaryRecord{}, nil
+		},
+	}
+	server := testServer(t, ServeT{reporter: reporter})
+
+	resp, err := http.Get(server.URL + "/v1/summary?start=2024-01-10&end=2024-01-12")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	wantEnd := time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)
+	wantBaselineEnd := time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)
+	wantBaselineStart := time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)
+
+	if !gotOpts.End.Equal(wantEnd) {
+		t.Fatalf("End = %s, want %s", gotOpts.End, wantEnd)
+	}
+	if !gotOpts.BaselineEnd.Equal(wantBaselineEnd) {
+		t.Fatalf("BaselineEnd = %s, want %s (should default to the day before start)", gotOpts.BaselineEnd, wantBaselineEnd)
+	}
+	if !gotOpts.BaselineStart.Equal(wantBaselineStart) {
+		t.Fatalf("BaselineStart = %s, want %s (should match the 3-day span of start..end)", gotOpts.BaselineStart, wantBaselineStart)
+	}
+}
+
+func TestHandleUsagesSingleDay(t *testing.T) {
+	var gotStart, gotEnd time.Time
+	reporter := fakeReportAPI{
+		usages: func(ctx context.Context, project string, start, end time.Time) ([]equinix.UsageRecord, error) {
+			gotStart, gotEnd = start, end
+			return []equinix.UsageRecord{{Name: "usage-1"}}, nil
+		},
+	}
+	server := testServer(t, ServeT{reporter: reporter})
+
+	resp, err := http.Get(server.URL + "/v1/usages?project=foo&start=2024-01-15&end=2024-01-15")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var usages []equinix.UsageRecord
+	if err := json.NewDecoder(resp.Body).Decode(&usages); err != nil {
+		t.Fatalf("error decoding response: %s", err)
+	}
+	if len(usages) != 1 || usages[0].Name != "usage-1" {
+		t.Fatalf("unexpected usages: %+v", usages)
+	}
+
+	wantDay := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if !gotStart.Equal(wantDay) || !gotEnd.Equal(wantDay) {
+		t.Fatalf("start=%s end=%s, want both %s", gotStart, gotEnd, wantDay)
+	}
+}
+
+func TestHandleUsagesRequiresProject(t *testing.T) {
+	server := testServer(t, ServeT{reporter: fakeReportAPI{}})
+
+	resp, err := http.Get(server.URL + "/v1/usages?start=2024-01-15&end=2024-01-15")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestBasicAuthRequired(t *testing.T) {
+	server := testServer(t, ServeT{
+		reporter: fakeReportAPI{
+			projects: func(ctx context.Context) ([]equinix.Project, error) { return nil, nil },
+		},
+		authUser: "user",
+		authPass: "pass",
+	})
+
+	resp, err := http.Get(server.URL + "/v1/projects")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without credentials, got %d", resp.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/projects", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req.SetBasicAuth("user", "pass")
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with valid credentials, got %d", resp.StatusCode)
+	}
+}
+
+func TestBasicAuthExemptsHealthz(t *testing.T) {
+	server := testServer(t, ServeT{authUser: "user", authPass: "pass"})
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected /healthz to bypass auth, got %d", resp.StatusCode)
+	}
+}
@@ -2,6 +2,10 @@ package common
 
 import "time"
 
+// ISO8601_FORMAT is the time.Parse/time.Format layout produced by
+// PartialToFullIso.
+const ISO8601_FORMAT = "2006-01-02T15:04:05.000-0700"
+
 // PartialToFullIso completes a partial ISO datetime. The partial can be:
 // * A date in YYYY-MM-DD format (00:00:00.000 UTC will be assumed)
 // * A timestamp in YYYY-MM-DDTHH:MM:SS format (UTC will be assumed)
@@ -1,15 +1,45 @@
 package equinix
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// DefaultConcurrency is the number of per-project GetUsages requests that
+// are allowed to run at the same time when no explicit Concurrency is set.
+const DefaultConcurrency = 4
+
+// DefaultMaxElapsedTime bounds how long a single request will keep retrying
+// transient failures before giving up, when no explicit MaxElapsedTime is set.
+const DefaultMaxElapsedTime = 2 * time.Minute
+
+const projectsPerPage = 1000
+
 type Equinix struct {
 	Token string
+
+	// Client is the HTTP client used to talk to api.equinix.com. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// Concurrency bounds how many per-project GetUsages requests are
+	// in flight at once. If zero, DefaultConcurrency is used.
+	Concurrency int
+
+	// MaxElapsedTime bounds how long a single request is retried before
+	// giving up. If zero, DefaultMaxElapsedTime is used.
+	MaxElapsedTime time.Duration
+
+	// BaseURL overrides the API root, for tests. If empty, the real
+	// Equinix Metal API is used.
+	BaseURL string
 }
 
 type Project struct {
@@ -17,8 +47,13 @@ type Project struct {
 	Name string `json:"name"`
 }
 
+type meta struct {
+	Next *string `json:"next"`
+}
+
 type Projects struct {
 	Projects []Project `json:"projects"`
+	Meta     meta      `json:"meta"`
 }
 
 type UsageRecord struct {
@@ -34,97 +69,278 @@ type UsageRecord struct {
 
 type UsageRecords struct {
 	Usages []UsageRecord `json:"usages"`
+	Meta   meta          `json:"meta"`
+}
+
+func (eq Equinix) client() *http.Client {
+	if eq.Client != nil {
+		return eq.Client
+	}
+	return http.DefaultClient
+}
+
+func (eq Equinix) concurrency() int {
+	if eq.Concurrency > 0 {
+		return eq.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+func (eq Equinix) maxElapsedTime() time.Duration {
+	if eq.MaxElapsedTime > 0 {
+		return eq.MaxElapsedTime
+	}
+	return DefaultMaxElapsedTime
+}
+
+func (eq Equinix) baseURL() string {
+	if eq.BaseURL != "" {
+		return eq.BaseURL
+	}
+	return "https://api.equinix.com"
 }
 
-func (eq Equinix) GetProjects() ([]Project, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest(
-		"GET",
-		"https://api.equinix.com/metal/v1/projects?page=1&per_page=1000&include=id,name",
-		nil,
+// GetProjects returns every project visible to the configured token,
+// following `meta.next` links until the API stops paginating.
+func (eq Equinix) GetProjects(ctx context.Context) ([]Project, error) {
+	url := fmt.Sprintf(
+		"%s/metal/v1/projects?page=1&per_page=%d&include=id,name",
+		eq.baseURL(),
+		projectsPerPage,
 	)
-	if err != nil {
-		return nil, fmt.Errorf("error while creating HTTP request: %w", err)
+
+	var projects []Project
+
+	for url != "" {
+		bytes, err := eq.getWithRetry(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("error while fetching projects: %w", err)
+		}
+
+		var page Projects
+		if err := json.Unmarshal(bytes, &page); err != nil {
+			return nil, fmt.Errorf("error while unmarshaling JSON response: %w", err)
+		}
+
+		projects = append(projects, page.Projects...)
+
+		if page.Meta.Next == nil {
+			break
+		}
+		url = *page.Meta.Next
 	}
 
-	req.Header.Add("X-Auth-Token", eq.Token)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error while making the HTTP request: %w", err)
+	return projects, nil
+}
+
+// GetUsages fetches usage records for each of the given projects between
+// startDate and endDate, keyed by project name. Per-project requests are
+// fanned out through a bounded worker pool (see Equinix.Concurrency) and
+// each request follows `meta.next` links until exhausted.
+func (eq Equinix) GetUsages(ctx context.Context, startDate time.Time, endDate time.Time, projects []Project) (map[string][]UsageRecord, error) {
+	type result struct {
+		project string
+		usages  []UsageRecord
+		err     error
 	}
-	defer resp.Body.Close()
 
-	bytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error while reading the response body: %w", err)
+	jobs := make(chan Project)
+	results := make(chan result)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := eq.concurrency()
+	if workers > len(projects) {
+		workers = len(projects)
+	}
+	if workers < 1 {
+		workers = 1
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf(
-			"HTTP error.\nStatus code: %d\nResponse body: %s",
-			resp.StatusCode,
-			string(bytes),
-		)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for project := range jobs {
+				usages, err := eq.getProjectUsages(ctx, startDate, endDate, project)
+				results <- result{project: project.Name, usages: usages, err: err}
+			}
+		}()
 	}
 
-	var projects Projects
+	go func() {
+		defer close(jobs)
+		for _, project := range projects {
+			select {
+			case jobs <- project:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	usages := make(map[string][]UsageRecord, len(projects))
+	var firstErr error
+
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error while getting usages for project %s: %w", r.project, r.err)
+				cancel()
+			}
+			continue
+		}
+		usages[r.project] = r.usages
+	}
 
-	err = json.Unmarshal(bytes, &projects)
-	if err != nil {
-		return nil, fmt.Errorf("error while unmarshaling JSON response: %w", err)
+	if firstErr != nil {
+		return nil, firstErr
 	}
 
-	return projects.Projects, nil
+	return usages, nil
 }
 
-func (eq Equinix) GetUsages(startDate time.Time, endDate time.Time, projects []Project) (map[Project][]UsageRecord, error) {
-	client := &http.Client{}
-	usages := make(map[Project][]UsageRecord)
+func (eq Equinix) getProjectUsages(ctx context.Context, startDate time.Time, endDate time.Time, project Project) ([]UsageRecord, error) {
+	url := fmt.Sprintf(
+		"%s/metal/v1/projects/%s/usages?created[after]=%sT00:00:00&created[before]=%sT23:59:59.999",
+		eq.baseURL(),
+		project.Id,
+		startDate.Format("2006-01-02"),
+		endDate.Format("2006-01-02"),
+	)
+
+	var usages []UsageRecord
 
-	for _, project := range projects {
-		uri := fmt.Sprintf(
-			"https://api.equinix.com/metal/v1/projects/%s/usages?created[after]=%sT00:00:00&created[before]=%sT23:59:59.999",
-			project.Id,
-			startDate.Format("2006-01-02"),
-			endDate.Format("2006-01-02"),
-		)
-		req, err := http.NewRequest(
-			"GET",
-			uri,
-			nil,
-		)
+	for url != "" {
+		bytes, err := eq.getWithRetry(ctx, url)
 		if err != nil {
-			return nil, fmt.Errorf("error while creating HTTP request for project %s: %w", project.Id, err)
+			return nil, err
 		}
 
-		req.Header.Add("X-Auth-Token", eq.Token)
-		resp, err := client.Do(req)
+		var page UsageRecords
+		if err := json.Unmarshal(bytes, &page); err != nil {
+			return nil, fmt.Errorf("error while unmarshaling JSON response: %w", err)
+		}
+
+		usages = append(usages, page.Usages...)
+
+		if page.Meta.Next == nil {
+			break
+		}
+		url = *page.Meta.Next
+	}
+
+	return usages, nil
+}
+
+// getWithRetry performs a GET request against url, retrying transient
+// failures (429, 5xx, and connection-level errors) with exponential backoff
+// and jitter, honoring any Retry-After header, until the request succeeds,
+// ctx is done, or MaxElapsedTime elapses.
+func (eq Equinix) getWithRetry(ctx context.Context, url string) ([]byte, error) {
+	deadline := time.Now().Add(eq.maxElapsedTime())
+	b := backoff{base: 500 * time.Millisecond, max: 30 * time.Second}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			return nil, fmt.Errorf("error while making the HTTP request for project %s: %w", project.Id, err)
+			return nil, fmt.Errorf("error while creating HTTP request: %w", err)
 		}
-		defer resp.Body.Close()
+		req.Header.Add("X-Auth-Token", eq.Token)
 
-		bytes, err := io.ReadAll(resp.Body)
+		resp, err := eq.client().Do(req)
 		if err != nil {
-			return nil, fmt.Errorf("error while reading the response body for project %s: %w", project.Id, err)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("error while making the HTTP request: %w", err)
+			}
+			if !sleep(ctx, b.next(attempt)) {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, fmt.Errorf("error while reading the response body: %w", readErr)
+		}
+
+		if resp.StatusCode == 200 {
+			return body, nil
 		}
-		if resp.StatusCode != 200 {
+
+		if !isRetryable(resp.StatusCode) || time.Now().After(deadline) {
 			return nil, fmt.Errorf(
-				"HTTP error for project %s.\nStatus code: %d\nResponse body: %s",
-				project.Id,
+				"HTTP error.\nStatus code: %d\nResponse body: %s",
 				resp.StatusCode,
-				string(bytes),
+				string(body),
 			)
 		}
 
-		var records UsageRecords
-
-		err = json.Unmarshal(bytes, &records)
-		if err != nil {
-			return nil, fmt.Errorf("error while unmarshaling JSON response for project %s: %w", project.Id, err)
+		delay := b.next(attempt)
+		if retryAfter, ok := retryAfterDelay(resp.Header.Get("Retry-After")); ok {
+			delay = retryAfter
+		}
+		if !sleep(ctx, delay) {
+			return nil, ctx.Err()
 		}
+	}
+}
+
+func isRetryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryAfterDelay parses a Retry-After header value given in seconds. It
+// does not support the HTTP-date form, which api.equinix.com does not use.
+func retryAfterDelay(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// sleep waits for d or until ctx is canceled, whichever comes first. It
+// returns false if ctx was canceled.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
-		usages[project] = records.Usages
+// backoff computes exponential backoff delays (base * 2^attempt, capped at
+// max) with +-20% jitter to avoid thundering-herd retries.
+type backoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+func (b backoff) next(attempt int) time.Duration {
+	d := b.base << attempt
+	if d <= 0 || d > b.max {
+		d = b.max
 	}
 
-	return usages, nil
+	jitter := 0.8 + rand.Float64()*0.4 // +-20%
+	return time.Duration(float64(d) * jitter)
 }
@@ -0,0 +1,197 @@
+package equinix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testEquinix(t *testing.T, server *httptest.Server) Equinix {
+	t.Cleanup(server.Close)
+	return Equinix{
+		Token:          "test-token",
+		Client:         server.Client(),
+		MaxElapsedTime: time.Second,
+		BaseURL:        server.URL,
+	}
+}
+
+func TestGetProjectsPaginates(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/metal/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		next := server.URL + "/metal/v1/projects/page2"
+		json.NewEncoder(w).Encode(Projects{
+			Projects: []Project{{Id: "1", Name: "one"}},
+			Meta:     meta{Next: &next},
+		})
+	})
+	mux.HandleFunc("/metal/v1/projects/page2", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Projects{
+			Projects: []Project{{Id: "2", Name: "two"}},
+		})
+	})
+
+	eq := testEquinix(t, server)
+
+	projects, err := eq.GetProjects(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("expected 2 projects, got %d: %+v", len(projects), projects)
+	}
+}
+
+func TestGetProjectsRetriesOnRateLimit(t *testing.T) {
+	var attempts int32
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/metal/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(Projects{Projects: []Project{{Id: "1", Name: "one"}}})
+	})
+
+	eq := testEquinix(t, server)
+
+	projects, err := eq.GetProjects(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestGetProjectsGivesUpAfterMaxElapsedTime(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/metal/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	eq := testEquinix(t, server)
+
+	_, err := eq.GetProjects(context.Background())
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected error to mention status code, got: %s", err)
+	}
+}
+
+func TestGetProjectsCancellation(t *testing.T) {
+	block := make(chan struct{})
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/metal/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+
+	eq := testEquinix(t, server)
+	t.Cleanup(func() { close(block) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := eq.GetProjects(ctx)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+func TestGetUsagesFansOutPerProject(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/metal/v1/projects/1/usages", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(UsageRecords{Usages: []UsageRecord{{Name: "a"}}})
+	})
+	mux.HandleFunc("/metal/v1/projects/2/usages", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(UsageRecords{Usages: []UsageRecord{{Name: "b"}}})
+	})
+
+	eq := testEquinix(t, server)
+	eq.Concurrency = 2
+
+	usages, err := eq.GetUsages(
+		context.Background(),
+		time.Now(),
+		time.Now(),
+		[]Project{{Id: "1", Name: "one"}, {Id: "2", Name: "two"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(usages) != 2 || len(usages["one"]) != 1 || len(usages["two"]) != 1 {
+		t.Fatalf("unexpected usages: %+v", usages)
+	}
+}
+
+func TestGetUsagesReturnsWhenNonLastProjectErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	for _, id := range []string{"1", "3", "4", "5", "6"} {
+		mux.HandleFunc("/metal/v1/projects/"+id+"/usages", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(UsageRecords{Usages: []UsageRecord{{Name: "ok"}}})
+		})
+	}
+	mux.HandleFunc("/metal/v1/projects/2/usages", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	eq := testEquinix(t, server)
+	eq.Concurrency = 1
+
+	projects := []Project{
+		{Id: "1", Name: "one"},
+		{Id: "2", Name: "two"},
+		{Id: "3", Name: "three"},
+		{Id: "4", Name: "four"},
+		{Id: "5", Name: "five"},
+		{Id: "6", Name: "six"},
+	}
+
+	done := make(chan struct{})
+	var err error
+	go func() {
+		_, err = eq.GetUsages(context.Background(), time.Now(), time.Now(), projects)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetUsages did not return within deadline; likely deadlocked")
+	}
+
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected error to mention status code, got: %s", err)
+	}
+}
@@ -1,28 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ipfs-shipyard/equinix-billing-tools/cmd"
-	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
 )
 
 func main() {
-	commands := map[string]func(equinix.Equinix) cmd.Command{
+	commands := map[string]func([]string) (cmd.Command, error){
 		"cost_summary": cmd.CostSummary,
 		"bigquery":     cmd.UploadToBigquery,
-	}
-
-	equinixToken := os.Getenv("EQUINIX_TOKEN")
-
-	if len(equinixToken) == 0 {
-		fmt.Fprintf(os.Stderr, "EQUINIX_TOKEN environment variable is not set")
-		os.Exit(1)
-	}
-
-	eq := equinix.Equinix{
-		Token: equinixToken,
+		"serve":        cmd.Serve,
+		"prometheus":   cmd.Prometheus,
 	}
 
 	if len(os.Args) == 1 {
@@ -31,7 +24,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	command, found := commands[os.Args[1]]
+	ctor, found := commands[os.Args[1]]
 
 	if !found {
 		fmt.Fprintf(os.Stderr, "Invalid subcommand %s. Valid subcommands: \n", os.Args[1])
@@ -39,10 +32,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	command(eq).Run()
+	command, err := ctor(os.Args[2:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := command.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-func printSubcommands(commands map[string]func(equinix.Equinix) cmd.Command) {
+func printSubcommands(commands map[string]func([]string) (cmd.Command, error)) {
 	for k := range commands {
 		fmt.Fprintf(os.Stderr, "    %s\n", k)
 	}
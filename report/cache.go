@@ -0,0 +1,101 @@
+package report
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+)
+
+// usageCache caches a single project-day's usage records for ttl. A ttl of
+// zero disables caching (every lookup misses).
+type usageCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[usageCacheKey]usageCacheEntry
+}
+
+type usageCacheKey struct {
+	project string
+	day     string // YYYY-MM-DD
+}
+
+type usageCacheEntry struct {
+	usages    []equinix.UsageRecord
+	expiresAt time.Time
+}
+
+func newUsageCache(ttl time.Duration) *usageCache {
+	return &usageCache{
+		ttl:     ttl,
+		entries: make(map[usageCacheKey]usageCacheEntry),
+	}
+}
+
+func (c *usageCache) get(project string, day time.Time) ([]equinix.UsageRecord, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	key := usageCacheKey{project: project, day: day.Format("2006-01-02")}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[key]
+	if !found || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.usages, true
+}
+
+func (c *usageCache) set(project string, day time.Time, usages []equinix.UsageRecord) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	key := usageCacheKey{project: project, day: day.Format("2006-01-02")}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = usageCacheEntry{usages: usages, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// usagesForRange returns usage records for each project across
+// [start, end] (inclusive of both endpoints), fetching one day at a time so
+// that already-cached days are never re-requested.
+func (r *Reporter) usagesForRange(ctx context.Context, projects []equinix.Project, start time.Time, end time.Time) (map[string][]equinix.UsageRecord, error) {
+	usages := make(map[string][]equinix.UsageRecord, len(projects))
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		var uncached []equinix.Project
+
+		for _, p := range projects {
+			if cached, ok := r.cache.get(p.Name, day); ok {
+				usages[p.Name] = append(usages[p.Name], cached...)
+			} else {
+				uncached = append(uncached, p)
+			}
+		}
+
+		if len(uncached) == 0 {
+			continue
+		}
+
+		fetched, err := r.equinix.GetUsages(ctx, day, day, uncached)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range uncached {
+			records := fetched[p.Name]
+			r.cache.set(p.Name, day, records)
+			usages[p.Name] = append(usages[p.Name], records...)
+		}
+	}
+
+	return usages, nil
+}
@@ -0,0 +1,216 @@
+// Package report contains the summarization logic shared by the
+// cost_summary CLI command and the serve HTTP command.
+package report
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+)
+
+type SummaryRecord struct {
+	Price        float64
+	Quantity     float64
+	Total        float64
+	BasePrice    float64
+	BaseQuantity float64
+	BaseTotal    float64
+}
+
+type ReportType string
+
+// Report type
+const (
+	ReservationsReport    ReportType = "reservations" // Display hardware reservations
+	NonReservationsReport ReportType = ""             // Display everything except hardware reservations
+)
+
+func (t ReportType) includeUsage(usage equinix.UsageRecord) bool {
+	return (t == ReservationsReport && usage.Type == "HardwareReservation") ||
+		(t == NonReservationsReport && usage.Type != "HardwareReservation")
+}
+
+// SummaryOptions describes a summary request, shared by the CLI and the
+// HTTP API.
+type SummaryOptions struct {
+	ReportType    ReportType
+	OnlyGateways  bool
+	Start         time.Time
+	End           time.Time
+	BaselineStart time.Time
+	BaselineEnd   time.Time
+}
+
+// ReportAPI is the summarization surface shared by the cost_summary CLI
+// command, the serve HTTP command, and the prometheus exporter, so all
+// three walk the same code path.
+type ReportAPI interface {
+	Summary(ctx context.Context, opts SummaryOptions) (map[string]SummaryRecord, SummaryRecord, error)
+	Projects(ctx context.Context) ([]equinix.Project, error)
+	Usages(ctx context.Context, project string, start time.Time, end time.Time) ([]equinix.UsageRecord, error)
+	UsagesForProjects(ctx context.Context, projects []equinix.Project, start time.Time, end time.Time) (map[string][]equinix.UsageRecord, error)
+}
+
+// Reporter is the default ReportAPI implementation, backed by the Equinix
+// API and an in-process per-day usage cache.
+type Reporter struct {
+	equinix equinix.Equinix
+	cache   *usageCache
+}
+
+// NewReporter builds a Reporter that caches per-day usage responses for ttl.
+// A ttl of zero disables caching.
+func NewReporter(eq equinix.Equinix, ttl time.Duration) *Reporter {
+	return &Reporter{
+		equinix: eq,
+		cache:   newUsageCache(ttl),
+	}
+}
+
+func (r *Reporter) Projects(ctx context.Context) ([]equinix.Project, error) {
+	return r.equinix.GetProjects(ctx)
+}
+
+// Usages returns the usage records for a single project between start and
+// end, going through the per-day cache.
+func (r *Reporter) Usages(ctx context.Context, project string, start time.Time, end time.Time) ([]equinix.UsageRecord, error) {
+	projects, err := r.equinix.GetProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		if p.Name == project {
+			usages, err := r.usagesForRange(ctx, []equinix.Project{p}, start, end)
+			if err != nil {
+				return nil, err
+			}
+			return usages[project], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// UsagesForProjects returns the usage records for each of projects between
+// start and end (inclusive), going through the per-day cache. It is the
+// batch counterpart to Usages, for callers -- like the prometheus exporter
+// -- that already have the project list and want every project's usages
+// without paying for a GetProjects call per project.
+func (r *Reporter) UsagesForProjects(ctx context.Context, projects []equinix.Project, start time.Time, end time.Time) (map[string][]equinix.UsageRecord, error) {
+	return r.usagesForRange(ctx, projects, start, end)
+}
+
+// Summary fetches usages for opts.Start..opts.End and the baseline window,
+// then summarizes them per project. It is the shared implementation behind
+// both the cost_summary CLI command and the serve HTTP command.
+func (r *Reporter) Summary(ctx context.Context, opts SummaryOptions) (map[string]SummaryRecord, SummaryRecord, error) {
+	projects, err := r.equinix.GetProjects(ctx)
+	if err != nil {
+		return nil, SummaryRecord{}, err
+	}
+
+	if opts.OnlyGateways {
+		projs := make([]equinix.Project, 0, 1)
+		for _, p := range projects {
+			if p.Name == "gateway" {
+				projs = append(projs, p)
+			}
+		}
+		projects = projs
+	} else {
+		sort.Slice(
+			projects,
+			func(a, b int) bool {
+				return strings.ToUpper(projects[a].Name) < strings.ToUpper(projects[b].Name)
+			},
+		)
+	}
+
+	usages, err := r.usagesForRange(ctx, projects, opts.Start, opts.End)
+	if err != nil {
+		return nil, SummaryRecord{}, err
+	}
+	baseline, err := r.usagesForRange(ctx, projects, opts.BaselineStart, opts.BaselineEnd)
+	if err != nil {
+		return nil, SummaryRecord{}, err
+	}
+
+	if opts.OnlyGateways {
+		usages = SplitGateways(usages)
+		baseline = SplitGateways(baseline)
+	}
+
+	perProjectSummary, totals := Summarize(opts.ReportType, baseline, usages)
+	return perProjectSummary, totals, nil
+}
+
+// SplitGateways splits the "gateway" project's usages between Kubo and LB
+// nodes, based on naming conventions used by the ipfs-shipyard gateway
+// fleet.
+func SplitGateways(usages map[string][]equinix.UsageRecord) map[string][]equinix.UsageRecord {
+	gateways := usages["gateway"]
+	usages = make(map[string][]equinix.UsageRecord)
+
+	for _, u := range gateways {
+		var k string
+
+		if strings.HasPrefix(u.Name, "ipfs-") || (u.Type == "HardwareReservation" && strings.Contains(u.Plan, "medium")) {
+			k = "gateway-kubo"
+		} else if strings.HasPrefix(u.Name, "gateway-") || (u.Type == "HardwareReservation" && strings.Contains(u.Plan, "small")) {
+			k = "gateway-lb"
+		}
+
+		usages[k] = append(usages[k], u)
+	}
+
+	return usages
+}
+
+// Summarize aggregates usage and baseline records per project, filtered by
+// reportType, and returns both the per-project breakdown and the grand
+// total across all projects.
+func Summarize(
+	reportType ReportType,
+	baseline map[string][]equinix.UsageRecord,
+	usages map[string][]equinix.UsageRecord,
+) (map[string]SummaryRecord, SummaryRecord) {
+	perProjectSummary := make(map[string]SummaryRecord)
+
+	var totals SummaryRecord
+
+	for project, projectUsages := range usages {
+		var summary SummaryRecord
+		baseUsages := baseline[project]
+
+		for _, usage := range projectUsages {
+			if reportType.includeUsage(usage) {
+				summary.Price += usage.Price
+				summary.Quantity += usage.Quantity
+				summary.Total += usage.Total
+			}
+		}
+
+		for _, usage := range baseUsages {
+			if reportType.includeUsage(usage) {
+				summary.BasePrice += usage.Price
+				summary.BaseQuantity += usage.Quantity
+				summary.BaseTotal += usage.Total
+			}
+		}
+
+		totals.Price += summary.Price
+		totals.Quantity += summary.Quantity
+		totals.Total += summary.Total
+		totals.BasePrice += summary.BasePrice
+		totals.BaseQuantity += summary.BaseQuantity
+		totals.BaseTotal += summary.BaseTotal
+
+		perProjectSummary[project] = summary
+	}
+
+	return perProjectSummary, totals
+}
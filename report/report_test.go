@@ -0,0 +1,48 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/ipfs-shipyard/equinix-billing-tools/equinix"
+)
+
+func TestSummarizeFiltersByReportType(t *testing.T) {
+	usages := map[string][]equinix.UsageRecord{
+		"proj": {
+			{Type: "HardwareReservation", Total: 10},
+			{Type: "Other", Total: 5},
+		},
+	}
+	baseline := map[string][]equinix.UsageRecord{
+		"proj": {
+			{Type: "HardwareReservation", Total: 8},
+		},
+	}
+
+	perProject, totals := Summarize(ReservationsReport, baseline, usages)
+
+	if perProject["proj"].Total != 10 {
+		t.Fatalf("expected reservations-only total 10, got %v", perProject["proj"].Total)
+	}
+	if perProject["proj"].BaseTotal != 8 {
+		t.Fatalf("expected baseline total 8, got %v", perProject["proj"].BaseTotal)
+	}
+	if totals.Total != 10 {
+		t.Fatalf("expected grand total 10, got %v", totals.Total)
+	}
+}
+
+func TestSplitGateways(t *testing.T) {
+	usages := map[string][]equinix.UsageRecord{
+		"gateway": {
+			{Name: "ipfs-1"},
+			{Name: "gateway-lb-1"},
+		},
+	}
+
+	split := SplitGateways(usages)
+
+	if len(split["gateway-kubo"]) != 1 || len(split["gateway-lb"]) != 1 {
+		t.Fatalf("unexpected split: %+v", split)
+	}
+}